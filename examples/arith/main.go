@@ -0,0 +1,142 @@
+// Command arith is a small end-to-end example showing how the lexer and
+// parser packages compose: it lexes and evaluates simple arithmetic
+// expressions like "2 + 3 * (4 - 1)".
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	lexer "github.com/sjhitchner/lexer"
+	"github.com/sjhitchner/lexer/parser"
+)
+
+const (
+	tokNumber lexer.TokenType = iota + 1
+	tokPlus
+	tokMinus
+	tokStar
+	tokSlash
+	tokLParen
+	tokRParen
+)
+
+const digits = "0123456789"
+
+func lexExpr(l *lexer.Lexer) lexer.StateFunc {
+	for {
+		r := l.Next()
+		switch {
+		case r == lexer.EOF:
+			l.Emit(lexer.TokenEOF)
+			return nil
+		case r == ' ' || r == '\t' || r == '\n':
+			l.Ignore()
+		case r >= '0' && r <= '9':
+			l.AcceptRun(digits)
+			l.Emit(tokNumber)
+		case r == '+':
+			l.Emit(tokPlus)
+		case r == '-':
+			l.Emit(tokMinus)
+		case r == '*':
+			l.Emit(tokStar)
+		case r == '/':
+			l.Emit(tokSlash)
+		case r == '(':
+			l.Emit(tokLParen)
+		case r == ')':
+			l.Emit(tokRParen)
+		default:
+			return l.Errorf("unexpected character %q", r)
+		}
+	}
+}
+
+// exprParser is a recursive-descent parser over a parser.TokenStream.
+type exprParser struct {
+	s    parser.TokenStream
+	errs parser.ErrorList
+}
+
+func (p *exprParser) parseExpr() float64 {
+	v := p.parseTerm()
+	for {
+		tok := p.s.Peek()
+		switch tok.Type {
+		case tokPlus:
+			p.s.Next()
+			v += p.parseTerm()
+		case tokMinus:
+			p.s.Next()
+			v -= p.parseTerm()
+		default:
+			return v
+		}
+	}
+}
+
+func (p *exprParser) parseTerm() float64 {
+	v := p.parseFactor()
+	for {
+		tok := p.s.Peek()
+		switch tok.Type {
+		case tokStar:
+			p.s.Next()
+			v *= p.parseFactor()
+		case tokSlash:
+			p.s.Next()
+			v /= p.parseFactor()
+		default:
+			return v
+		}
+	}
+}
+
+func (p *exprParser) parseFactor() float64 {
+	tok := p.s.Next()
+	switch tok.Type {
+	case tokNumber:
+		v, err := strconv.ParseFloat(tok.Value, 64)
+		if err != nil {
+			p.errs.Add(tok.Pos, "invalid number %q", tok.Value)
+			return 0
+		}
+		return v
+	case tokLParen:
+		v := p.parseExpr()
+		if _, err := p.s.Expect(tokRParen); err != nil {
+			p.errs.Errors = append(p.errs.Errors, err)
+		}
+		return v
+	case tokMinus:
+		return -p.parseFactor()
+	case lexer.TokenError:
+		p.errs.Add(tok.Pos, "%s", tok.Value)
+		return 0
+	default:
+		p.errs.Add(tok.Pos, "unexpected token %s", tok)
+		return 0
+	}
+}
+
+func eval(input string) (float64, error) {
+	p := &exprParser{s: parser.NewStream(lexer.NewLexerString(input, lexExpr))}
+	v := p.parseExpr()
+	return v, p.errs.Err()
+}
+
+func main() {
+	input := "2 + 3 * (4 - 1)"
+	if len(os.Args) > 1 {
+		input = os.Args[1]
+	}
+
+	v, err := eval(input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Printf("%s = %g\n", input, v)
+}
@@ -2,8 +2,8 @@ package lexer
 
 import (
 	"fmt"
+	"io"
 	"strings"
-	"unicode/utf8"
 )
 
 const (
@@ -15,9 +15,22 @@ const (
 
 type TokenType int
 
+// Pos describes a location in the input: the byte offset from the start
+// plus the 1-based line and column it falls on.
+type Pos struct {
+	Offset int
+	Line   int
+	Col    int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("line %d, col %d", p.Line, p.Col)
+}
+
 type Token struct {
 	Type  TokenType
 	Value string
+	Pos   Pos
 }
 
 func (t Token) String() string {
@@ -35,49 +48,157 @@ func (t Token) String() string {
 
 type StateFunc func(*Lexer) StateFunc
 
-// Lexer
+// Lexer reads runes from an io.RuneReader, so input can be fed from a
+// string, a file, or any other streaming source.
 type Lexer struct {
-	input  string
-	start  int
-	pos    int
-	width  int
-	state  StateFunc
-	tokens chan Token
+	r io.RuneReader
+
+	// unread holds runes pushed back by Backup, most recently backed-up
+	// last, so the next Next call replays them before reading from r.
+	// unreadSize holds the matching byte width of each rune in unread,
+	// so replaying a backed-up rune doesn't have to re-derive it.
+	unread     []rune
+	unreadSize []int
+
+	// buf holds the runes consumed since the last Ignore/Emit, i.e. the
+	// value of the token currently being scanned. bufPos holds, for each
+	// rune in buf, the position just before that rune was consumed, so
+	// Backup can restore it exactly. bufSize holds the byte width
+	// ReadRune reported for each rune in buf, since utf8.RuneLen(r)
+	// disagrees with it for utf8.RuneError on invalid input.
+	buf     []rune
+	bufPos  []Pos
+	bufSize []int
+
+	atEOF bool
+
+	offset, line, col                int
+	startOffset, startLine, startCol int
+
+	state StateFunc
+
+	// ring is a small circular buffer of emitted-but-not-yet-returned
+	// tokens, replacing the channel NextToken used to drain from.
+	ring              []Token
+	ringHead, ringLen int
+
+	// terminal holds the TokenEOF or TokenError token emitted by the
+	// state function that returned a nil StateFunc, so NextToken has
+	// something to keep returning once the ring buffer drains.
+	terminal Token
+
+	// Trace, if non-nil, is invoked on emit, skip, error, and
+	// rune-consumed events for debugging a grammar's state functions.
+	Trace func(event string, tok *Token)
+}
+
+func (t *Lexer) trace(event string, tok *Token) {
+	if t.Trace != nil {
+		t.Trace(event, tok)
+	}
+}
+
+const ringInitialCap = 4
+
+// push enqueues a token onto the ring buffer, growing it if full.
+func (t *Lexer) push(tok Token) {
+	if t.ringLen == len(t.ring) {
+		t.growRing()
+	}
+	t.ring[(t.ringHead+t.ringLen)%len(t.ring)] = tok
+	t.ringLen++
 }
 
-// No copying, just a slice
-// Channel usage adds some overhead could use ring buffer
-func NewLexer(input string, initialState StateFunc) *Lexer {
+func (t *Lexer) growRing() {
+	newCap := len(t.ring) * 2
+	if newCap == 0 {
+		newCap = ringInitialCap
+	}
+	newRing := make([]Token, newCap)
+	for i := 0; i < t.ringLen; i++ {
+		newRing[i] = t.ring[(t.ringHead+i)%len(t.ring)]
+	}
+	t.ring = newRing
+	t.ringHead = 0
+}
+
+// pop removes and returns the oldest token in the ring buffer.
+func (t *Lexer) pop() (Token, bool) {
+	if t.ringLen == 0 {
+		return Token{}, false
+	}
+	tok := t.ring[t.ringHead]
+	t.ringHead = (t.ringHead + 1) % len(t.ring)
+	t.ringLen--
+	return tok, true
+}
+
+// NewLexer creates a Lexer that reads from r. Wrap an io.Reader with
+// bufio.NewReader to satisfy io.RuneReader.
+func NewLexer(r io.RuneReader, initialState StateFunc) *Lexer {
 	l := &Lexer{
-		input:  input,
-		tokens: make(chan Token, 2),
-		state:  initialState,
+		r:         r,
+		ring:      make([]Token, ringInitialCap),
+		state:     initialState,
+		line:      1,
+		col:       1,
+		startLine: 1,
+		startCol:  1,
+	}
+	return l
+}
+
+// NewLexerString is a convenience constructor for lexing an in-memory string.
+func NewLexerString(input string, initialState StateFunc) *Lexer {
+	return NewLexer(strings.NewReader(input), initialState)
+}
+
+// NewTracingLexer creates a Lexer whose Trace hook writes one line per
+// event to w, for debugging a grammar's state functions.
+func NewTracingLexer(r io.RuneReader, initialState StateFunc, w io.Writer) *Lexer {
+	l := NewLexer(r, initialState)
+	l.Trace = func(event string, tok *Token) {
+		if tok != nil {
+			fmt.Fprintf(w, "%s: %s\n", event, tok)
+			return
+		}
+		fmt.Fprintln(w, event)
 	}
 	return l
 }
 
+// NextToken returns the next token, driving state functions until one
+// emits. Once a state function returns a nil StateFunc (end of input or
+// an unrecoverable error), NextToken keeps returning the terminal token
+// it emitted on every subsequent call instead of invoking a nil state.
 func (t *Lexer) NextToken() Token {
 	for {
-		select {
-		case item := <-t.tokens:
-			return item
-		default:
-			t.state = t.state(t)
+		if tok, ok := t.pop(); ok {
+			return tok
 		}
+		if t.state == nil {
+			return t.terminal
+		}
+		t.state = t.state(t)
 	}
-	panic("should never get here")
 }
 
 func (t *Lexer) Emit(i TokenType) {
-	if t.pos > len(t.input) {
-		t.tokens <- Token{TokenError, "Reached end of input unexpectantly"}
-		return
+	tok := Token{
+		Type:  i,
+		Value: string(t.buf),
+		Pos:   Pos{Offset: t.startOffset, Line: t.startLine, Col: t.startCol},
 	}
+	if i == TokenEOF {
+		t.terminal = tok
+	}
+	t.push(tok)
+	t.trace("emit", &tok)
 
-	fmt.Printf("E '%s'\n", t.input[t.start:t.pos])
-
-	t.tokens <- Token{i, t.input[t.start:t.pos]}
-	t.start = t.pos
+	t.buf = t.buf[:0]
+	t.bufPos = t.bufPos[:0]
+	t.bufSize = t.bufSize[:0]
+	t.startOffset, t.startLine, t.startCol = t.offset, t.line, t.col
 }
 
 func (l *Lexer) Peek() rune {
@@ -87,33 +208,77 @@ func (l *Lexer) Peek() rune {
 }
 
 func (t *Lexer) Next() rune {
-	r, w := utf8.DecodeRuneInString(t.input[t.pos:])
-	t.width = w
-	t.pos += t.width
+	t.atEOF = false
 
-	if int(t.pos) >= len(t.input) {
-		t.width = 0
-		return EOF
+	var r rune
+	var size int
+	if n := len(t.unread); n > 0 {
+		r = t.unread[n-1]
+		size = t.unreadSize[n-1]
+		t.unread = t.unread[:n-1]
+		t.unreadSize = t.unreadSize[:n-1]
+	} else {
+		var err error
+		r, size, err = t.r.ReadRune()
+		if err != nil {
+			t.atEOF = true
+			return EOF
+		}
 	}
 
-	fmt.Println("R", string(r))
+	pos := Pos{Offset: t.offset, Line: t.line, Col: t.col}
+	t.bufPos = append(t.bufPos, pos)
+	t.buf = append(t.buf, r)
+	t.bufSize = append(t.bufSize, size)
+
+	t.offset += size
+	if r == '\n' {
+		t.line++
+		t.col = 1
+	} else {
+		t.col++
+	}
+
+	tok := Token{Value: string(r), Pos: pos}
+	t.trace("consume", &tok)
 	return r
 }
 
 func (t *Lexer) Skip() {
 	t.Next()
 	t.Ignore()
+	t.trace("skip", nil)
 }
 
 // ignore skips over the pending input before this point.
 func (l *Lexer) Ignore() {
-	l.start = l.pos
+	l.buf = l.buf[:0]
+	l.bufPos = l.bufPos[:0]
+	l.bufSize = l.bufSize[:0]
+	l.startOffset, l.startLine, l.startCol = l.offset, l.line, l.col
 }
 
-// backup steps back one rune.
-// Can be called only once per call of next.
+// backup steps back one rune. Unlike a single-slot backup, this may be
+// called repeatedly in a row to back up over any number of runes
+// consumed since the last Ignore/Emit, not just the most recent Next.
 func (l *Lexer) Backup() {
-	l.pos -= l.width
+	if l.atEOF {
+		l.atEOF = false
+		return
+	}
+	n := len(l.buf)
+	if n == 0 {
+		return
+	}
+	r := l.buf[n-1]
+	size := l.bufSize[n-1]
+	pos := l.bufPos[n-1]
+	l.buf = l.buf[:n-1]
+	l.bufSize = l.bufSize[:n-1]
+	l.bufPos = l.bufPos[:n-1]
+	l.unread = append(l.unread, r)
+	l.unreadSize = append(l.unreadSize, size)
+	l.offset, l.line, l.col = pos.Offset, pos.Line, pos.Col
 }
 
 // accept consumes the next rune
@@ -133,15 +298,101 @@ func (l *Lexer) AcceptRun(valid string) {
 	l.Backup()
 }
 
+// Matches reports whether str appears next in the input, without
+// consuming it either way.
 func (l *Lexer) Matches(str string) bool {
-	if strings.HasPrefix(l.input[l.pos:], str) {
-		l.pos += len(str)
+	return l.peekMatches(str)
+}
+
+// AcceptString consumes str if it appears next in the input.
+func (l *Lexer) AcceptString(str string) bool {
+	if !l.peekMatches(str) {
+		return false
+	}
+	for range []rune(str) {
+		l.Next()
+	}
+	return true
+}
+
+// AcceptAny consumes the longest of strs that matches next in the input,
+// returning the matched string and true, or "" and false if none match.
+func (l *Lexer) AcceptAny(strs ...string) (string, bool) {
+	best := ""
+	for _, s := range strs {
+		if l.peekMatches(s) && len(s) > len(best) {
+			best = s
+		}
+	}
+	if best == "" {
+		return "", false
+	}
+	for range []rune(best) {
+		l.Next()
+	}
+	return best, true
+}
+
+// Expect consumes str if it appears next in the input, or emits a
+// TokenError via Errorf and returns the resulting error otherwise.
+func (l *Lexer) Expect(str string) error {
+	if l.AcceptString(str) {
+		return nil
+	}
+	l.Errorf("expected %q", str)
+	return fmt.Errorf("expected %q", str)
+}
+
+// peekMatches reports whether str appears next in the input, consuming
+// and then backing up the runes it inspected.
+func (l *Lexer) peekMatches(str string) bool {
+	runes := []rune(str)
+	peeked := make([]rune, 0, len(runes))
+	matched := true
+	for _, want := range runes {
+		r := l.Next()
+		if r == EOF {
+			matched = false
+			l.Backup() // step back over EOF itself
+			break
+		}
+		peeked = append(peeked, r)
+		if r != want {
+			matched = false
+			break
+		}
+	}
+	for range peeked {
+		l.Backup()
+	}
+	return matched
+}
+
+// AcceptFunc consumes the next rune if pred reports true for it.
+func (l *Lexer) AcceptFunc(pred func(rune) bool) bool {
+	if pred(l.Next()) {
+		return true
 	}
+	l.Backup()
 	return false
 }
 
+// AcceptRunFunc consumes a run of runes for which pred reports true.
+func (l *Lexer) AcceptRunFunc(pred func(rune) bool) {
+	for pred(l.Next()) {
+	}
+	l.Backup()
+}
+
 func (t *Lexer) Errorf(format string, args ...interface{}) StateFunc {
-	t.tokens <- Token{TokenError, fmt.Sprintf(format, args...)}
+	tok := Token{
+		Type:  TokenError,
+		Value: fmt.Sprintf(format, args...),
+		Pos:   Pos{Offset: t.startOffset, Line: t.startLine, Col: t.startCol},
+	}
+	t.terminal = tok
+	t.push(tok)
+	t.trace("error", &tok)
 	return nil
 }
 
@@ -0,0 +1,80 @@
+package lexer
+
+import (
+	"testing"
+	"unicode"
+)
+
+// wordsAndNumbers is a small representative grammar: runs of letters,
+// runs of digits, and single-rune punctuation/whitespace tokens.
+const (
+	tokWord TokenType = iota + 1
+	tokNumber
+	tokPunct
+)
+
+func lexWordsAndNumbers(l *Lexer) StateFunc {
+	r := l.Next()
+	switch {
+	case r == EOF:
+		l.Emit(TokenEOF)
+		return nil
+	case unicode.IsLetter(r):
+		l.AcceptRun("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
+		l.Emit(tokWord)
+	case unicode.IsDigit(r):
+		l.AcceptRun("0123456789")
+		l.Emit(tokNumber)
+	default:
+		l.Emit(tokPunct)
+	}
+	return lexWordsAndNumbers
+}
+
+func benchmarkInput() string {
+	s := ""
+	for i := 0; i < 2000; i++ {
+		s += "hello 1234 world, 5678. "
+	}
+	return s
+}
+
+func BenchmarkNextToken(b *testing.B) {
+	input := benchmarkInput()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l := NewLexerString(input, lexWordsAndNumbers)
+		for {
+			tok := l.NextToken()
+			if tok.Type == TokenEOF {
+				break
+			}
+		}
+	}
+}
+
+// BenchmarkRingBufferPushPop and BenchmarkChannelPushPop isolate the one
+// thing that changed between the old and new NextToken: how a token
+// crosses from Emit to its caller. The old Lexer used a chan Token of
+// capacity 2; the new one uses the ring buffer's push/pop. Benchmarking
+// them side by side is what demonstrates the throughput improvement,
+// since the old Lexer itself no longer exists to benchmark directly.
+func BenchmarkRingBufferPushPop(b *testing.B) {
+	l := NewLexerString("", nil)
+	tok := Token{Type: tokWord, Value: "hello"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		l.push(tok)
+		l.pop()
+	}
+}
+
+func BenchmarkChannelPushPop(b *testing.B) {
+	ch := make(chan Token, 2)
+	tok := Token{Type: tokWord, Value: "hello"}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ch <- tok
+		<-ch
+	}
+}
@@ -0,0 +1,116 @@
+package lexer
+
+import (
+	"testing"
+	"unicode/utf8"
+)
+
+func TestNextTracksLineAndColumn(t *testing.T) {
+	l := NewLexerString("ab\ncd", nil)
+
+	// l.line/l.col are the position just *after* the rune Next returns,
+	// as used by bufPos/startLine/startCol for the following rune.
+	want := []struct {
+		r    rune
+		line int
+		col  int
+	}{
+		{'a', 1, 2},
+		{'b', 1, 3},
+		{'\n', 2, 1},
+		{'c', 2, 2},
+		{'d', 2, 3},
+	}
+
+	for i, w := range want {
+		r := l.Next()
+		if r != w.r {
+			t.Fatalf("rune %d: got %q, want %q", i, r, w.r)
+		}
+		if l.line != w.line || l.col != w.col {
+			t.Fatalf("rune %d (%q): got line %d col %d, want line %d col %d", i, r, l.line, l.col, w.line, w.col)
+		}
+	}
+}
+
+func TestNextTracksByteOffsetAcrossUTF8(t *testing.T) {
+	l := NewLexerString("é f", nil) // é is a 2-byte rune
+
+	if r := l.Next(); r != 'é' || l.offset != 2 {
+		t.Fatalf("got rune %q offset %d; want 'é' offset 2", r, l.offset)
+	}
+	if r := l.Next(); r != ' ' || l.offset != 3 {
+		t.Fatalf("got rune %q offset %d; want ' ' offset 3", r, l.offset)
+	}
+	if r := l.Next(); r != 'f' || l.offset != 4 {
+		t.Fatalf("got rune %q offset %d; want 'f' offset 4", r, l.offset)
+	}
+}
+
+func TestBackupRestoresLineAndColAcrossNewline(t *testing.T) {
+	l := NewLexerString("a\nb", nil)
+
+	l.Next() // 'a' -> line 1, col 2
+	l.Next() // '\n' -> line 2, col 1
+	if l.line != 2 || l.col != 1 {
+		t.Fatalf("after consuming newline: got line %d col %d, want line 2 col 1", l.line, l.col)
+	}
+
+	l.Backup()
+	if l.line != 1 || l.col != 2 {
+		t.Fatalf("Backup did not restore position across a newline: got line %d col %d, want line 1 col 2", l.line, l.col)
+	}
+
+	if r := l.Next(); r != '\n' {
+		t.Fatalf("expected to re-read the newline after Backup, got %q", r)
+	}
+	if l.line != 2 || l.col != 1 {
+		t.Fatalf("after re-reading newline: got line %d col %d, want line 2 col 1", l.line, l.col)
+	}
+}
+
+func TestEmitRecordsStartPosition(t *testing.T) {
+	const tokWord TokenType = 100
+
+	l := NewLexerString("ab\ncd", nil)
+
+	l.Next()
+	l.Next()
+	l.Emit(tokWord)
+	tok, ok := l.pop()
+	if !ok {
+		t.Fatal("expected a token in the ring buffer")
+	}
+	if tok.Value != "ab" || tok.Pos != (Pos{Offset: 0, Line: 1, Col: 1}) {
+		t.Fatalf("unexpected first token: %+v", tok)
+	}
+
+	l.Next() // '\n'
+	l.Next() // 'c'
+	l.Next() // 'd'
+	l.Emit(tokWord)
+	tok2, ok := l.pop()
+	if !ok {
+		t.Fatal("expected a second token in the ring buffer")
+	}
+	if tok2.Value != "\ncd" || tok2.Pos != (Pos{Offset: 2, Line: 1, Col: 3}) {
+		t.Fatalf("unexpected second token: %+v", tok2)
+	}
+}
+
+func TestNextOffsetSurvivesInvalidUTF8(t *testing.T) {
+	l := NewLexerString("a\xffb", nil)
+
+	if r := l.Next(); r != 'a' || l.offset != 1 {
+		t.Fatalf("got rune %q offset %d; want 'a' offset 1", r, l.offset)
+	}
+	// \xff is not valid UTF-8 on its own; ReadRune consumes exactly one
+	// byte and reports utf8.RuneError, which must not be mistaken for
+	// the 3-byte width utf8.RuneLen(utf8.RuneError) would suggest.
+	if r := l.Next(); r != utf8.RuneError || l.offset != 2 {
+		t.Fatalf("got rune %q offset %d; want RuneError offset 2", r, l.offset)
+	}
+	if r := l.Next(); r != 'b' || l.offset != 3 {
+		t.Fatalf("got rune %q offset %d; want 'b' offset 3", r, l.offset)
+	}
+}
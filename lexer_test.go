@@ -0,0 +1,106 @@
+package lexer
+
+import (
+	"testing"
+	"unicode"
+)
+
+func TestMatchesReportsTrueWithoutConsuming(t *testing.T) {
+	l := NewLexerString("hello world", nil)
+	if !l.Matches("hello") {
+		t.Fatal("expected Matches to report true for a matching prefix")
+	}
+	if !l.AcceptString("hello") {
+		t.Fatal("Matches should not have consumed input on a match")
+	}
+}
+
+func TestMatchesReportsFalseWithoutConsuming(t *testing.T) {
+	l := NewLexerString("hello world", nil)
+	if l.Matches("world") {
+		t.Fatal("expected Matches to report false for a non-matching prefix")
+	}
+	if !l.AcceptString("hello") {
+		t.Fatal("Matches should not have consumed input on a mismatch")
+	}
+}
+
+func TestAcceptString(t *testing.T) {
+	l := NewLexerString("foobar", nil)
+	if !l.AcceptString("foo") {
+		t.Fatal("expected AcceptString to match and consume foo")
+	}
+	if !l.AcceptString("bar") {
+		t.Fatal("expected AcceptString to match and consume bar")
+	}
+	if l.AcceptString("baz") {
+		t.Fatal("expected AcceptString to fail past the end of input")
+	}
+}
+
+func TestAcceptAnyLongestMatchWins(t *testing.T) {
+	l := NewLexerString("<=foo", nil)
+	got, ok := l.AcceptAny("<", "<=")
+	if !ok || got != "<=" {
+		t.Fatalf("AcceptAny = %q, %v; want %q, true", got, ok, "<=")
+	}
+	if !l.AcceptString("foo") {
+		t.Fatal("AcceptAny should have consumed only the matched string")
+	}
+}
+
+func TestAcceptAnyNoMatch(t *testing.T) {
+	l := NewLexerString("foo", nil)
+	if _, ok := l.AcceptAny("<", "<="); ok {
+		t.Fatal("expected AcceptAny to report no match")
+	}
+	if !l.AcceptString("foo") {
+		t.Fatal("AcceptAny should not have consumed input on no match")
+	}
+}
+
+func TestExpectEmitsErrorTokenOnMismatch(t *testing.T) {
+	l := NewLexerString("abc", nil)
+	if err := l.Expect("xyz"); err == nil {
+		t.Fatal("expected Expect to return an error on mismatch")
+	}
+	tok := l.NextToken()
+	if tok.Type != TokenError {
+		t.Fatalf("expected Expect to emit a TokenError token, got %v", tok)
+	}
+}
+
+func TestExpectConsumesOnMatch(t *testing.T) {
+	l := NewLexerString("abc", nil)
+	if err := l.Expect("abc"); err != nil {
+		t.Fatalf("expected Expect to succeed, got %v", err)
+	}
+}
+
+func TestAcceptFuncMatchesAndConsumes(t *testing.T) {
+	l := NewLexerString("9x", nil)
+	if !l.AcceptFunc(unicode.IsDigit) {
+		t.Fatal("expected AcceptFunc to match a digit")
+	}
+	if !l.AcceptString("x") {
+		t.Fatal("AcceptFunc should have consumed only the matched digit")
+	}
+}
+
+func TestAcceptFuncNoMatchDoesNotConsume(t *testing.T) {
+	l := NewLexerString("x9", nil)
+	if l.AcceptFunc(unicode.IsDigit) {
+		t.Fatal("expected AcceptFunc to report no match")
+	}
+	if !l.AcceptString("x9") {
+		t.Fatal("AcceptFunc should not have consumed input on no match")
+	}
+}
+
+func TestAcceptRunFuncConsumesUnicodeDigits(t *testing.T) {
+	l := NewLexerString("123abc", nil)
+	l.AcceptRunFunc(unicode.IsDigit)
+	if !l.AcceptString("abc") {
+		t.Fatal("AcceptRunFunc should have consumed only the run of digits")
+	}
+}
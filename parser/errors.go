@@ -0,0 +1,35 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+
+	lexer "github.com/sjhitchner/lexer"
+)
+
+// ErrorList accumulates positioned parse errors so a parser can keep
+// going after a bad token and report everything it found at the end.
+type ErrorList struct {
+	Errors []error
+}
+
+// Add records a new error at pos.
+func (e *ErrorList) Add(pos lexer.Pos, format string, args ...interface{}) {
+	e.Errors = append(e.Errors, fmt.Errorf("%s: %s", pos, fmt.Sprintf(format, args...)))
+}
+
+// Err returns the ErrorList as an error, or nil if it is empty.
+func (e *ErrorList) Err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
+
+func (e *ErrorList) Error() string {
+	strs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		strs[i] = err.Error()
+	}
+	return strings.Join(strs, "\n")
+}
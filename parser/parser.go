@@ -0,0 +1,90 @@
+// Package parser provides pull-parser primitives that pair with a
+// lexer.Lexer: a buffered TokenStream and an ErrorList for accumulating
+// positioned parse errors.
+package parser
+
+import (
+	"fmt"
+
+	lexer "github.com/sjhitchner/lexer"
+)
+
+// TokenStream is the interface grammar writers code against, so parsers
+// can be tested against fakes without a real Lexer.
+type TokenStream interface {
+	Peek() lexer.Token
+	Next() lexer.Token
+	Backup()
+	Expect(t lexer.TokenType) (lexer.Token, error)
+	ExpectOneOf(types ...lexer.TokenType) (lexer.Token, error)
+}
+
+// Stream adapts a *lexer.Lexer into a TokenStream with one token of
+// lookahead and one token of lookback.
+type Stream struct {
+	lex    *lexer.Lexer
+	last   lexer.Token
+	peeked *lexer.Token
+	backed bool
+}
+
+// NewStream wraps lex in a Stream.
+func NewStream(lex *lexer.Lexer) *Stream {
+	return &Stream{lex: lex}
+}
+
+// Next returns the next token, consuming it.
+func (s *Stream) Next() lexer.Token {
+	if s.backed {
+		s.backed = false
+		return s.last
+	}
+	if s.peeked != nil {
+		tok := *s.peeked
+		s.peeked = nil
+		s.last = tok
+		return tok
+	}
+	s.last = s.lex.NextToken()
+	return s.last
+}
+
+// Peek returns the next token without consuming it.
+func (s *Stream) Peek() lexer.Token {
+	if s.backed {
+		return s.last
+	}
+	if s.peeked == nil {
+		tok := s.lex.NextToken()
+		s.peeked = &tok
+	}
+	return *s.peeked
+}
+
+// Backup rewinds the last token returned by Next so the next call to
+// Next or Peek returns it again. Can be called only once per call of Next.
+func (s *Stream) Backup() {
+	s.backed = true
+}
+
+// Expect consumes the next token and checks it is of type t, returning a
+// positioned error if not.
+func (s *Stream) Expect(t lexer.TokenType) (lexer.Token, error) {
+	tok := s.Next()
+	if tok.Type != t {
+		return tok, fmt.Errorf("%s: expected token type %d, got %s", tok.Pos, t, tok)
+	}
+	return tok, nil
+}
+
+// ExpectOneOf consumes the next token and checks it is one of types,
+// returning a positioned error if not.
+func (s *Stream) ExpectOneOf(types ...lexer.TokenType) (lexer.Token, error) {
+	tok := s.Next()
+	for _, t := range types {
+		if tok.Type == t {
+			return tok, nil
+		}
+	}
+	return tok, fmt.Errorf("%s: unexpected token %s", tok.Pos, tok)
+}
@@ -0,0 +1,141 @@
+package parser
+
+import (
+	"testing"
+
+	lexer "github.com/sjhitchner/lexer"
+)
+
+const (
+	tokA lexer.TokenType = iota + 1
+	tokB
+	tokC
+)
+
+// lexABC emits tokA, tokB, tokC for the runes 'a', 'b', 'c' and ignores
+// everything else, terminating on EOF.
+func lexABC(l *lexer.Lexer) lexer.StateFunc {
+	for {
+		switch l.Next() {
+		case 'a':
+			l.Emit(tokA)
+		case 'b':
+			l.Emit(tokB)
+		case 'c':
+			l.Emit(tokC)
+		case lexer.EOF:
+			l.Emit(lexer.TokenEOF)
+			return nil
+		default:
+			l.Ignore()
+		}
+	}
+}
+
+func newTestStream(input string) *Stream {
+	return NewStream(lexer.NewLexerString(input, lexABC))
+}
+
+func TestStreamPeekThenBackup(t *testing.T) {
+	s := newTestStream("ab")
+
+	if tok := s.Peek(); tok.Type != tokA {
+		t.Fatalf("Peek = %s, want tokA", tok)
+	}
+	if tok := s.Next(); tok.Type != tokA {
+		t.Fatalf("Next = %s, want tokA", tok)
+	}
+
+	s.Backup()
+
+	if tok := s.Next(); tok.Type != tokA {
+		t.Fatalf("Next after Backup = %s, want tokA again", tok)
+	}
+	if tok := s.Next(); tok.Type != tokB {
+		t.Fatalf("Next = %s, want tokB", tok)
+	}
+}
+
+func TestStreamNextThenBackupThenPeek(t *testing.T) {
+	s := newTestStream("ab")
+
+	if tok := s.Next(); tok.Type != tokA {
+		t.Fatalf("Next = %s, want tokA", tok)
+	}
+
+	s.Backup()
+
+	if tok := s.Peek(); tok.Type != tokA {
+		t.Fatalf("Peek after Backup = %s, want tokA", tok)
+	}
+	if tok := s.Next(); tok.Type != tokA {
+		t.Fatalf("Next after Peek = %s, want tokA", tok)
+	}
+	if tok := s.Next(); tok.Type != tokB {
+		t.Fatalf("Next = %s, want tokB", tok)
+	}
+}
+
+func TestStreamExpectSuccess(t *testing.T) {
+	s := newTestStream("a")
+
+	tok, err := s.Expect(tokA)
+	if err != nil {
+		t.Fatalf("Expect = %v, want no error", err)
+	}
+	if tok.Type != tokA {
+		t.Fatalf("Expect returned %s, want tokA", tok)
+	}
+}
+
+func TestStreamExpectMismatch(t *testing.T) {
+	s := newTestStream("a")
+
+	_, err := s.Expect(tokB)
+	if err == nil {
+		t.Fatal("expected Expect to return an error on mismatch")
+	}
+	want := "line 1, col 1: expected token type 2, got "
+	if got := err.Error(); len(got) < len(want) || got[:len(want)] != want {
+		t.Fatalf("Expect error = %q, want prefix %q", got, want)
+	}
+}
+
+func TestStreamExpectOneOf(t *testing.T) {
+	s := newTestStream("c")
+
+	tok, err := s.ExpectOneOf(tokA, tokB, tokC)
+	if err != nil {
+		t.Fatalf("ExpectOneOf = %v, want no error", err)
+	}
+	if tok.Type != tokC {
+		t.Fatalf("ExpectOneOf returned %s, want tokC", tok)
+	}
+
+	s2 := newTestStream("a")
+	_, err = s2.ExpectOneOf(tokB, tokC)
+	if err == nil {
+		t.Fatal("expected ExpectOneOf to return an error when no type matches")
+	}
+}
+
+func TestErrorListAddAndErr(t *testing.T) {
+	var errs ErrorList
+
+	if err := errs.Err(); err != nil {
+		t.Fatalf("Err on empty list = %v, want nil", err)
+	}
+
+	errs.Add(lexer.Pos{Line: 1, Col: 2}, "bad token %s", "x")
+	errs.Add(lexer.Pos{Line: 3, Col: 4}, "another error")
+
+	err := errs.Err()
+	if err == nil {
+		t.Fatal("expected Err to return a non-nil error once errors were added")
+	}
+
+	want := "line 1, col 2: bad token x\nline 3, col 4: another error"
+	if got := err.Error(); got != want {
+		t.Fatalf("Err().Error() = %q, want %q", got, want)
+	}
+}